@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/libgit2/git2go"
+)
+
+func TestHostnameMatches(t *testing.T) {
+	cases := []struct {
+		name       string
+		hostsField string
+		hostname   string
+		want       bool
+	}{
+		{"exact match", "github.com", "github.com", true},
+		{"no match", "github.com", "gitlab.com", false},
+		{"second of several", "github.com,gitlab.com", "gitlab.com", true},
+		{"empty field", "", "github.com", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := hostnameMatches(c.hostsField, c.hostname); got != c.want {
+				t.Errorf("hostnameMatches(%q, %q) = %v, want %v", c.hostsField, c.hostname, got, c.want)
+			}
+		})
+	}
+}
+
+func TestKnownHostsAccept(t *testing.T) {
+	key := []byte("fake-ssh-public-key-bytes")
+	sum := sha256.Sum256(key)
+	encoded := base64.StdEncoding.EncodeToString(key)
+	knownHosts := "github.com ssh-rsa " + encoded + "\n# comment line\ngitlab.com ssh-rsa " + base64.StdEncoding.EncodeToString([]byte("other-key"))
+
+	cases := []struct {
+		name     string
+		hostname string
+		hashSum  [32]byte
+		want     bool
+	}{
+		{"matching host and key", "github.com", sum, true},
+		{"matching host, wrong key", "github.com", sha256.Sum256([]byte("wrong-key")), false},
+		{"unknown host", "bitbucket.org", sum, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hostkey := git.HostkeyCertificate{HashSHA256: c.hashSum}
+			if got := knownHostsAccept(knownHosts, c.hostname, hostkey); got != c.want {
+				t.Errorf("knownHostsAccept(_, %q, _) = %v, want %v", c.hostname, got, c.want)
+			}
+		})
+	}
+}
@@ -31,7 +31,30 @@ type Source struct {
 	Branch     string `json:"branch"`
 	TagFilter  string `json:"tag_filter"`
 	PathSearch []string `json:"paths"`
+	IgnorePaths []string `json:"ignore_paths"`
 	PrivateKey string `json:"private_key"`
+	DisableLfs bool   `json:"disable_lfs"`
+	CommitVerificationKeys   []string `json:"commit_verification_keys"`
+	CommitVerificationKeyIds []string `json:"commit_verification_key_ids"`
+	GpgKeyserver             string   `json:"gpg_keyserver"`
+	FirstParentOnly          bool     `json:"first_parent_only"`
+	TagSort    string `json:"tag_sort"`
+	TagPrefix  string `json:"tag_prefix"`
+	PreRelease bool   `json:"pre_release"`
+	Depth               int                `json:"depth"`
+	Submodules          SubmoduleSelection `json:"submodules"`
+	SubmoduleRecursive  bool               `json:"submodule_recursive"`
+	Username    string      `json:"username"`
+	Password    string      `json:"password"`
+	HttpsTunnel HttpsTunnel `json:"https_tunnel"`
+	KnownHosts  string      `json:"known_hosts"`
+}
+
+type HttpsTunnel struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"user"`
+	Password string `json:"password"`
 }
 
 type MetadataJson struct {
@@ -52,16 +75,21 @@ var sshKeyPath = "/root/.ssh/"
 func Init(config Config) {
 	if !exists(sshKeyPath) {
 		os.MkdirAll(sshKeyPath, 0755)
-		ioutil.WriteFile(sshKeyPath+"id_rsa", []byte(config.Input.Source.PrivateKey), 0600)
-		createSshPubKey()
+		if config.Input.Source.PrivateKey != "" {
+			ioutil.WriteFile(sshKeyPath+"id_rsa", []byte(config.Input.Source.PrivateKey), 0600)
+			createSshPubKey()
+		}
+	}
+	if config.Input.Source.KnownHosts != "" {
+		ioutil.WriteFile(sshKeyPath+"known_hosts", []byte(config.Input.Source.KnownHosts), 0600)
 	}
 	if config.Input.Source.Branch == "" {
 		config.Input.Source.Branch = "master"
 	}
 	if exists(config.Path + "/.git") {
-		fetchRepo(config.Path)
+		fetchRepo(config.Path, config.Input.Source)
 	} else {
-		cloneRepo(config.Input.Source.Url, config.Input.Source.Branch, config.Path)
+		cloneRepo(config.Input.Source.Url, config.Input.Source.Branch, config.Path, config.Input.Source)
 	}
 }
 
@@ -70,12 +98,12 @@ func Check(config Config) RefResult {
 		config.Input.Source.Branch  = "master"
 	}
 	if config.Input.Source.TagFilter != "" {
-		return checkTagFilter(config)
+		return filterVerified(config, checkTagFilter(config), true)
 	}
 	if config.Input.Source.PathSearch != nil {
-		return checkPaths(config)
+		return filterVerified(config, checkPaths(config), false)
 	} else {
-		return checkCommit(config)
+		return filterVerified(config, checkCommit(config), false)
 	}
 	return nil
 }
@@ -92,24 +120,20 @@ func Checkout(config Config) {
 	} else {
 		oid, _ = git.NewOid(config.Input.Version.Ref)
 	}
+	if verificationConfigured(config.Input.Source) {
+		home := gnupgHome(config.Input.Source)
+		ok, _ := verifyRef(config.Path, home, config.Input.Version.Ref, obj != nil)
+		os.RemoveAll(home)
+		if !ok {
+			log.Fatal("refusing to check out unsigned or untrusted ref ", config.Input.Version.Ref)
+		}
+	}
+
 	repo.SetHeadDetached(oid)
 	repo.CheckoutHead(&git.CheckoutOpts{Strategy: git.CheckoutForce})
 	defer repo.Free()
 
-}
-
-func checkPaths(config Config) RefResult {
-	if config.Input.Version.Ref == "" {
-		return checkCommit(config)
-	}
-	for _, pathSearch := range config.Input.Source.PathSearch{
-		for _, pf := range diff(config) {
-			if pf == pathSearch {
-				return checkCommit(config)
-			}
-		}
-	}
-	return nil
+	checkoutLfs(config)
 }
 
 func checkCommit(config Config) RefResult {
@@ -183,6 +207,19 @@ func GetMetaData(path string, input Payload) RefResult {
 	message["value"] = o.Message()
 
 	result = append(result, commit, author, whenCommit, branch, tag, message)
+
+	if verificationConfigured(input.Source) {
+		home := gnupgHome(input.Source)
+		_, signer := verifyRef(path, home, input.Version.Ref, obj != nil)
+		os.RemoveAll(home)
+		if signer != "" {
+			verifiedBy := make(map[string]string)
+			verifiedBy["name"] = "verified_by"
+			verifiedBy["value"] = signer
+			result = append(result, verifiedBy)
+		}
+	}
+
 	defer repo.Free()
 	return result
 }
@@ -190,6 +227,10 @@ func GetMetaData(path string, input Payload) RefResult {
 func checkTagFilter(config Config) RefResult {
 	list := listTags(config.Path, config.Input.Source.TagFilter)
 
+	if config.Input.Source.TagSort == "semver" || config.Input.Source.TagSort == "version" {
+		return checkSemverTags(list, config)
+	}
+
 	if config.Input.Version.Ref != "" {
 		return lastTags(list, config)
 	}
@@ -218,15 +259,6 @@ func createSshPubKey() {
 	ioutil.WriteFile(sshKeyPath+"id_rsa.pub", []byte(out), 0644)
 }
 
-func credentialsCallback(url string, username string, allowedTypes git.CredType) (git.ErrorCode, *git.Cred) {
-	ret, cred := git.NewCredSshKey("git", sshKeyPath+"id_rsa.pub", sshKeyPath+"id_rsa", "")
-	return git.ErrorCode(ret), &cred
-}
-
-func certificateCheckCallback(cert *git.Certificate, valid bool, hostname string) git.ErrorCode {
-	return 0
-}
-
 func exists(path string) bool {
 	_, err := os.Stat(path)
 	if err != nil || os.IsNotExist(err) {
@@ -235,28 +267,23 @@ func exists(path string) bool {
 	return true
 }
 
-func cloneRepo(url, branch, path string) {
-	cloneOptions := &git.CloneOptions{}
-	cloneOptions.CheckoutBranch = branch
-	cloneOptions.FetchOptions = &git.FetchOptions{
-		RemoteCallbacks: git.RemoteCallbacks{
-			CredentialsCallback:      credentialsCallback,
-			CertificateCheckCallback: certificateCheckCallback,
-		},
-	}
-	_, err := git.Clone(url, path, cloneOptions)
-	if err != nil {
-		log.Fatal(err, url)
+func cloneRepo(url, branch, path string, source Source) {
+	if source.Depth > 0 {
+		cloneShallow(url, branch, path, source)
+	} else {
+		cloneOptions := &git.CloneOptions{}
+		cloneOptions.CheckoutBranch = branch
+		cloneOptions.FetchOptions = buildFetchOptions(source)
+		_, err := git.Clone(url, path, cloneOptions)
+		if err != nil {
+			log.Fatal(err, url)
+		}
 	}
+	updateSubmodules(path, source)
 }
 
-func fetchRepo(path string) {
-	FetchOptions := &git.FetchOptions{
-		RemoteCallbacks: git.RemoteCallbacks{
-			CredentialsCallback:      credentialsCallback,
-			CertificateCheckCallback: certificateCheckCallback,
-		},
-	}
+func fetchRepo(path string, source Source) {
+	FetchOptions := buildFetchOptions(source)
 	repo, err := git.OpenRepository(path)
 	if err != nil {
 		log.Fatal(err)
@@ -269,6 +296,7 @@ func fetchRepo(path string) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	updateSubmodules(path, source)
 }
 
 func tagWhen(repo *git.Repository, oid *git.Oid) int64 {
@@ -293,34 +321,54 @@ func tagWhen(repo *git.Repository, oid *git.Oid) int64 {
 	return 0
 }
 
-func lastCommits(config Config) RefResult  {
+// lastCommits returns the commits reachable from the tracked branch since
+// config.Input.Version.Ref, oldest first, using a revwalk seeded on the
+// remote branch tip and hidden at the previous version so that only
+// genuinely new, ancestry-ordered commits come back.
+func lastCommits(config Config) RefResult {
 	repo, err := git.OpenRepository(config.Path)
 	if err != nil {
 		log.Fatal(err)
 	}
-	odb, err := repo.Odb()
+	defer repo.Free()
+
+	walk, err := repo.Walk()
 	if err != nil {
 		log.Fatal(err)
 	}
-	var allCommitList []string
-	err = odb.ForEach(func(id *git.Oid) error {
-		obj, err := repo.Lookup(id)
-		if err != nil {
-			log.Fatal(err)
-		}
-		if obj.Type() == git.ObjectCommit {
-			allCommitList = append(allCommitList, obj.Id().String())
+	defer walk.Free()
+
+	walk.Sorting(git.SortTopological | git.SortTime)
+	if config.Input.Source.FirstParentOnly {
+		walk.SimplifyFirstParent()
+	}
+
+	if err := walk.PushRef("refs/remotes/origin/" + config.Input.Source.Branch); err != nil {
+		log.Fatal(err)
+	}
+
+	if previous, err := git.NewOid(config.Input.Version.Ref); err == nil {
+		if _, lookupErr := repo.LookupCommit(previous); lookupErr == nil {
+			if err := walk.Hide(previous); err != nil {
+				log.Fatal(err)
+			}
 		}
-		return nil
+	}
+
+	var newestFirst []string
+	err = walk.Iterate(func(commit *git.Commit) bool {
+		newestFirst = append(newestFirst, commit.Id().String())
+		return true
 	})
-	var result  RefResult
-	for _, c := range allCommitList {
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var result RefResult
+	for i := len(newestFirst) - 1; i >= 0; i-- {
 		ref := make(map[string]string)
-		ref["ref"] = c
-		result = append([]map[string]string{ref}, result...)
-		if config.Input.Version.Ref == c {
-			break
-		}
+		ref["ref"] = newestFirst[i]
+		result = append(result, ref)
 	}
 	return result
 }
@@ -386,76 +434,3 @@ func lastTags(listTag []Tag, config Config) RefResult {
 	return result
 }
 
-func lookupCommit(repo *git.Repository, ref string) *git.Tree {
-	oid, err := git.NewOid(ref)
-	if err != nil {
-		log.Fatal(err)
-	}
-	obj, err := repo.LookupCommit(oid)
-	if err != nil {
-		log.Fatal(err)
-	}
-	tree, err := repo.LookupTree(obj.TreeId())
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer tree.Free()
-	return tree
-}
-
-func diff(config Config) []string {
-	repo, err := git.OpenRepository(config.Path)
-	if err != nil {
-		log.Fatal(err)
-	}
-	localBranch, err := repo.LookupBranch("origin/"+config.Input.Source.Branch, git.BranchRemote)
-	if err != nil {
-		log.Fatal(err)
-	}
-	commit, err := repo.LookupCommit(localBranch.Target())
-	if err != nil {
-		log.Fatal(err)
-	}
-	originalTree, err := repo.LookupTree(commit.TreeId())
-	if err != nil {
-		log.Fatal(err)
-	}
-	refTree := lookupCommit(repo, config.Input.Version.Ref)
-	callbackInvoked := false
-	opts := git.DiffOptions{
-		NotifyCallback: func(diffSoFar *git.Diff, delta git.DiffDelta, matchedPathSpec string) error {
-			callbackInvoked = true
-			return nil
-		},
-	}
-	diff, err := repo.DiffTreeToTree(originalTree, refTree, &opts)
-	if err != nil {
-		log.Fatal(err)
-	}
-	files := make([]string, 0)
-	hunks := make([]git.DiffHunk, 0)
-	lines := make([]git.DiffLine, 0)
-	patches := make([]string, 0)
-	err = diff.ForEach(func(file git.DiffDelta, progress float64) (git.DiffForEachHunkCallback, error) {
-		patch, err := diff.Patch(len(patches))
-		if err != nil {
-			return nil, err
-		}
-		defer patch.Free()
-		patchStr, err := patch.String()
-		if err != nil {
-			return nil, err
-		}
-		patches = append(patches, patchStr)
-		files = append(files, file.OldFile.Path)
-		return func(hunk git.DiffHunk) (git.DiffForEachLineCallback, error) {
-			hunks = append(hunks, hunk)
-			return func(line git.DiffLine) error {
-				lines = append(lines, line)
-				return nil
-			}, nil
-		}, nil
-	}, git.DiffDetailLines)
-	defer repo.Free()
-	return files
-}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const gitLfsAttribute = "filter=lfs"
+
+// lfsEnabled reports whether the checked out tree declares LFS filters in
+// its .gitattributes, which is the same signal git-lfs itself uses to
+// decide whether a smudge is required.
+func lfsEnabled(path string) bool {
+	content, err := ioutil.ReadFile(path + "/.gitattributes")
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(content), gitLfsAttribute)
+}
+
+// checkoutLfs resolves LFS pointer files in config.Path into their real
+// content. libgit2 has no notion of LFS, so this shells out to the git-lfs
+// CLI using the same SSH identity written by Init.
+func checkoutLfs(config Config) {
+	if config.Input.Source.DisableLfs {
+		return
+	}
+	if !lfsEnabled(config.Path) {
+		return
+	}
+	runGitLfs(config.Path, config.Input.Source, "lfs", "fetch", "--all")
+	runGitLfs(config.Path, config.Input.Source, "lfs", "checkout")
+}
+
+func runGitLfs(path string, source Source, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = path
+	cmd.Env = append(os.Environ(), sshCommandEnv(source))
+	cmd.Env = append(cmd.Env, proxyEnv(source)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Fatal(string(out), err)
+	}
+}
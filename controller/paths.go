@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"github.com/bmatcuk/doublestar"
+	"github.com/libgit2/git2go"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkPaths walks every new commit since config.Input.Version.Ref (via the
+// same revwalk as checkCommit) and emits a ref for each one that changed at
+// least one file matching Source.PathSearch and not matching
+// Source.IgnorePaths, mirroring the standard git resource's include/exclude
+// semantics.
+func checkPaths(config Config) RefResult {
+	if config.Input.Version.Ref == "" {
+		return checkCommit(config)
+	}
+
+	repo, err := git.OpenRepository(config.Path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer repo.Free()
+
+	var result RefResult
+	for _, c := range lastCommits(config) {
+		changed := changedFiles(repo, c["ref"])
+		if !pathsMatch(changed, config.Input.Source.PathSearch, config.Input.Source.IgnorePaths) {
+			continue
+		}
+		ref := make(map[string]string)
+		ref["ref"] = c["ref"]
+		result = append(result, ref)
+	}
+	return result
+}
+
+// changedFiles returns the set of paths that ref's commit changed relative
+// to its first parent (or the empty tree, for a root commit).
+func changedFiles(repo *git.Repository, ref string) []string {
+	oid, err := git.NewOid(ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+	commit, err := repo.LookupCommit(oid)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var parentTree *git.Tree
+	if commit.ParentCount() > 0 {
+		parentTree, err = commit.Parent(0).Tree()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	diff, err := repo.DiffTreeToTree(parentTree, tree, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer diff.Free()
+
+	var files []string
+	err = diff.ForEach(func(file git.DiffDelta, progress float64) (git.DiffForEachHunkCallback, error) {
+		files = append(files, file.NewFile.Path)
+		return nil, nil
+	}, git.DiffDetailFiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return files
+}
+
+// pathsMatch reports whether any file in changedFiles matches one of the
+// paths globs and is not itself covered by an ignorePaths glob. With no
+// paths configured, every file counts as a match.
+func pathsMatch(changedFiles, paths, ignorePaths []string) bool {
+	for _, f := range changedFiles {
+		if len(paths) > 0 && !globMatchesAny(f, paths) {
+			continue
+		}
+		if globMatchesAny(f, ignorePaths) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func globMatchesAny(file string, globs []string) bool {
+	for _, g := range globs {
+		if ok, err := doublestar.Match(g, file); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
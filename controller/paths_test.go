@@ -0,0 +1,49 @@
+package controller
+
+import "testing"
+
+func TestGlobMatchesAny(t *testing.T) {
+	cases := []struct {
+		name  string
+		file  string
+		globs []string
+		want  bool
+	}{
+		{"no globs", "main.go", nil, false},
+		{"exact match", "main.go", []string{"main.go"}, true},
+		{"single star", "pkg/foo.go", []string{"pkg/*.go"}, true},
+		{"doublestar", "pkg/sub/foo.go", []string{"**/*.go"}, true},
+		{"doublestar directory", "charts/foo/templates/deploy.yaml", []string{"charts/foo/**"}, true},
+		{"no match", "README.md", []string{"**/*.go"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := globMatchesAny(c.file, c.globs); got != c.want {
+				t.Errorf("globMatchesAny(%q, %v) = %v, want %v", c.file, c.globs, got, c.want)
+			}
+		})
+	}
+}
+
+func TestPathsMatch(t *testing.T) {
+	cases := []struct {
+		name        string
+		changed     []string
+		paths       []string
+		ignorePaths []string
+		want        bool
+	}{
+		{"no filters match any change", []string{"README.md"}, nil, nil, true},
+		{"path matches", []string{"pkg/foo.go", "README.md"}, []string{"**/*.go"}, nil, true},
+		{"path doesn't match", []string{"README.md"}, []string{"**/*.go"}, nil, false},
+		{"ignored path excluded", []string{"docs/CHANGELOG.md"}, []string{"**/*.md"}, []string{"docs/**"}, false},
+		{"one of several files survives ignore", []string{"docs/CHANGELOG.md", "pkg/foo.go"}, []string{"**/*"}, []string{"docs/**"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := pathsMatch(c.changed, c.paths, c.ignorePaths); got != c.want {
+				t.Errorf("pathsMatch(%v, %v, %v) = %v, want %v", c.changed, c.paths, c.ignorePaths, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	log "github.com/sirupsen/logrus"
+)
+
+// semverTag pairs a Tag with its parsed semver.Version so ordering can
+// follow semver precedence instead of tagger/commit time.
+type semverTag struct {
+	Tag
+	version *semver.Version
+}
+
+// parseSemverTags strips prefix from each tag name and parses it as a
+// semver version, silently skipping tags that don't parse and, unless
+// includePreRelease is set, tags with a pre-release component.
+func parseSemverTags(tags []Tag, prefix string, includePreRelease bool) []semverTag {
+	var result []semverTag
+	for _, t := range tags {
+		name := strings.TrimPrefix(strings.TrimPrefix(t.Name, "refs/tags/"), prefix)
+		v, err := semver.NewVersion(name)
+		if err != nil {
+			continue
+		}
+		if v.Prerelease() != "" && !includePreRelease {
+			continue
+		}
+		result = append(result, semverTag{Tag: t, version: v})
+	}
+	return result
+}
+
+// checkSemverTags orders tags by semver precedence rather than time. With
+// no prior version it returns the single newest tag; with a prior version
+// it returns every tag strictly greater than it, oldest first.
+func checkSemverTags(list []Tag, config Config) RefResult {
+	parsed := parseSemverTags(list, config.Input.Source.TagPrefix, config.Input.Source.PreRelease)
+	if len(parsed) == 0 {
+		return nil
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		return parsed[i].version.LessThan(parsed[j].version)
+	})
+
+	if config.Input.Version.Ref == "" {
+		latest := parsed[len(parsed)-1]
+		ref := make(map[string]string)
+		ref["ref"] = strings.TrimPrefix(latest.Name, "refs/tags/")
+		return RefResult{ref}
+	}
+
+	currentName := strings.TrimPrefix(config.Input.Version.Ref, config.Input.Source.TagPrefix)
+	current, err := semver.NewVersion(currentName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var result RefResult
+	for _, t := range parsed {
+		if !t.version.GreaterThan(current) {
+			continue
+		}
+		ref := make(map[string]string)
+		ref["ref"] = strings.TrimPrefix(t.Name, "refs/tags/")
+		result = append(result, ref)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package controller
+
+import "testing"
+
+func TestParseSemverTags(t *testing.T) {
+	tags := []Tag{
+		{Name: "refs/tags/v1.2.3"},
+		{Name: "refs/tags/v1.3.0-rc1"},
+		{Name: "refs/tags/not-a-version"},
+		{Name: "refs/tags/v2.0.0"},
+	}
+
+	withoutPreRelease := parseSemverTags(tags, "v", false)
+	if len(withoutPreRelease) != 2 {
+		t.Fatalf("expected 2 parsed tags excluding pre-release and invalid, got %d: %v", len(withoutPreRelease), withoutPreRelease)
+	}
+
+	withPreRelease := parseSemverTags(tags, "v", true)
+	if len(withPreRelease) != 3 {
+		t.Fatalf("expected 3 parsed tags including pre-release, got %d: %v", len(withPreRelease), withPreRelease)
+	}
+}
+
+func TestCheckSemverTagsNoPriorVersion(t *testing.T) {
+	list := []Tag{
+		{Name: "refs/tags/v1.0.0"},
+		{Name: "refs/tags/v1.2.0"},
+		{Name: "refs/tags/v1.1.0"},
+	}
+	config := Config{Input: &Payload{Source: Source{TagPrefix: "v"}}}
+
+	result := checkSemverTags(list, config)
+	if len(result) != 1 || result[0]["ref"] != "v1.2.0" {
+		t.Fatalf("expected only the highest semver tag v1.2.0, got %v", result)
+	}
+}
+
+func TestCheckSemverTagsSinceVersion(t *testing.T) {
+	list := []Tag{
+		{Name: "refs/tags/v1.0.0"},
+		{Name: "refs/tags/v1.2.0"},
+		{Name: "refs/tags/v1.1.0"},
+		{Name: "refs/tags/v2.0.0"},
+	}
+	config := Config{Input: &Payload{
+		Source:  Source{TagPrefix: "v"},
+		Version: Ref{Ref: "v1.1.0"},
+	}}
+
+	result := checkSemverTags(list, config)
+	var refs []string
+	for _, r := range result {
+		refs = append(refs, r["ref"])
+	}
+	want := []string{"v1.2.0", "v2.0.0"}
+	if len(refs) != len(want) {
+		t.Fatalf("got %v, want %v", refs, want)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Fatalf("got %v, want %v", refs, want)
+		}
+	}
+}
+
+func TestCheckSemverTagsExcludesPreRelease(t *testing.T) {
+	list := []Tag{
+		{Name: "refs/tags/v1.0.0"},
+		{Name: "refs/tags/v1.1.0-rc1"},
+	}
+	config := Config{Input: &Payload{Source: Source{TagPrefix: "v"}}}
+
+	result := checkSemverTags(list, config)
+	if len(result) != 1 || result[0]["ref"] != "v1.0.0" {
+		t.Fatalf("expected pre-release tag to be excluded by default, got %v", result)
+	}
+}
@@ -0,0 +1,62 @@
+package controller
+
+import "testing"
+
+func withStubbedVerification(gnupgHome func(Source) string, verifyRef func(string, string, string, bool) (bool, string), fn func()) {
+	origGnupgHome, origVerifyRef := gnupgHomeFn, verifyRefFn
+	gnupgHomeFn, verifyRefFn = gnupgHome, verifyRef
+	defer func() { gnupgHomeFn, verifyRefFn = origGnupgHome, origVerifyRef }()
+	fn()
+}
+
+func TestFilterVerifiedNotConfigured(t *testing.T) {
+	config := Config{Input: &Payload{Source: Source{}}}
+	result := RefResult{{"ref": "abc"}, {"ref": "def"}}
+
+	got := filterVerified(config, result, false)
+	if len(got) != len(result) {
+		t.Fatalf("expected unfiltered passthrough when no verification keys configured, got %v", got)
+	}
+}
+
+func TestFilterVerifiedDropsUntrusted(t *testing.T) {
+	config := Config{Input: &Payload{Source: Source{CommitVerificationKeyIds: []string{"DEADBEEF"}}}}
+	result := RefResult{{"ref": "trusted"}, {"ref": "untrusted"}}
+
+	withStubbedVerification(
+		func(Source) string { return "/tmp/fake-gnupg-home" },
+		func(repoPath, home, ref string, isTag bool) (bool, string) {
+			return ref == "trusted", "Test Signer"
+		},
+		func() {
+			got := filterVerified(config, result, false)
+			if len(got) != 1 || got[0]["ref"] != "trusted" {
+				t.Fatalf("expected only the trusted ref to survive, got %v", got)
+			}
+		},
+	)
+}
+
+func TestFilterVerifiedDoesNotMutateVersionMaps(t *testing.T) {
+	config := Config{Input: &Payload{Source: Source{CommitVerificationKeyIds: []string{"DEADBEEF"}}}}
+	result := RefResult{{"ref": "trusted"}}
+
+	withStubbedVerification(
+		func(Source) string { return "/tmp/fake-gnupg-home" },
+		func(repoPath, home, ref string, isTag bool) (bool, string) {
+			return true, "Test Signer"
+		},
+		func() {
+			got := filterVerified(config, result, false)
+			if len(got) != 1 {
+				t.Fatalf("expected 1 entry, got %v", got)
+			}
+			if _, ok := got[0]["verified_by"]; ok {
+				t.Fatalf("filterVerified must not add verified_by to the version map, got %v", got[0])
+			}
+			if len(got[0]) != 1 {
+				t.Fatalf("expected entry to be untouched beyond its original ref key, got %v", got[0])
+			}
+		},
+	)
+}